@@ -4,17 +4,24 @@ package s3
 import (
 	"bytes"
 	"context"
+	"crypto/md5"
 	"encoding/base64"
 	"encoding/json"
 	"errors"
 	"fmt"
 	"io"
+	"io/ioutil"
 	"net/url"
+	"strconv"
 	"strings"
+	"sync"
+	"time"
 
 	"github.com/aws/aws-sdk-go/aws"
 	"github.com/aws/aws-sdk-go/aws/awserr"
 	"github.com/aws/aws-sdk-go/aws/credentials"
+	"github.com/aws/aws-sdk-go/aws/credentials/ec2rolecreds"
+	"github.com/aws/aws-sdk-go/aws/credentials/stscreds"
 	"github.com/aws/aws-sdk-go/aws/session"
 	"github.com/aws/aws-sdk-go/service/s3"
 	"github.com/aws/aws-sdk-go/service/s3/s3manager"
@@ -28,14 +35,28 @@ import (
 	"go.opencensus.io/trace"
 )
 
-// TODO we should encrypt these, user will have to supply a key though (or all
-// OSS users logs will be encrypted with same key unless they change it which
-// just seems mean...)
-
 // TODO do we need to use the v2 API? can't find BMC object store docs :/
 
 const (
 	contentType = "text/plain"
+
+	// sse modes accepted by the `sse` query param
+	sseNone = ""
+	sseS3   = "AES256"
+	sseKMS  = "aws:kms"
+	sseC    = "c"
+
+	// default number of concurrent GetCall workers used by GetCalls
+	defaultListConcurrency = 8
+
+	// default retention applied to the Object Lock default-retention rule
+	// when `object_lock=true` is set without an explicit `retention_days`
+	defaultObjectLockRetentionDays = 365
+
+	// defaults for the part size/concurrency GetLogRange fans a whole-log
+	// read out into, when `part_size_mb`/`concurrency` aren't set
+	defaultLogPartSizeMB      = 16
+	defaultLogPartConcurrency = 5
 )
 
 type store struct {
@@ -43,6 +64,34 @@ type store struct {
 	uploader   *s3manager.Uploader
 	downloader *s3manager.Downloader
 	bucket     string
+
+	// server-side encryption config, see parseSSE. sseCustomerKey is kept
+	// around decoded (not base64) so we don't have to redo this per request.
+	sseMode           string
+	sseKMSKeyID       string
+	sseCustomerKey    []byte
+	sseCustomerKeyMD5 string
+
+	// number of concurrent GetCall workers GetCalls fans out to, see
+	// `list_concurrency` query param
+	listConcurrency int
+
+	// storage class / ACL applied to every uploaded object, see
+	// `storage_class` and `acl` query params
+	storageClass string
+	acl          string
+
+	// upper bound on the ttl a caller can request for PresignGetLog, see
+	// `max_presign_ttl` query param. zero means unbounded.
+	maxPresignTTL time.Duration
+
+	// part size (bytes) and worker count GetLogRange fans a whole-log read
+	// out into ranged gets with, see `part_size_mb`/`concurrency` query
+	// params. These also configure uploader/downloader above; uploader
+	// handles both log and call uploads, while downloader is only used for
+	// call objects (small JSON) -- log reads go through GetLogRange directly.
+	logPartSize        int64
+	logPartConcurrency int
 }
 
 // decorator around the Reader interface that keeps track of the number of bytes read
@@ -58,9 +107,9 @@ func (cr *countingReader) Read(p []byte) (n int, err error) {
 	return n, err
 }
 
-func createStore(bucketName, endpoint, region, accessKeyID, secretAccessKey string, useSSL bool) *store {
+func createStore(bucketName, endpoint, region string, creds *credentials.Credentials, useSSL bool, partSizeMB, concurrency int) *store {
 	config := &aws.Config{
-		Credentials:      credentials.NewStaticCredentials(accessKeyID, secretAccessKey, ""),
+		Credentials:      creds,
 		Endpoint:         aws.String(endpoint),
 		Region:           aws.String(region),
 		DisableSSL:       aws.Bool(!useSSL),
@@ -69,10 +118,127 @@ func createStore(bucketName, endpoint, region, accessKeyID, secretAccessKey stri
 	client := s3.New(session.Must(session.NewSession(config)))
 
 	return &store{
-		client:     client,
-		uploader:   s3manager.NewUploaderWithClient(client),
-		downloader: s3manager.NewDownloaderWithClient(client),
-		bucket:     bucketName,
+		client: client,
+		uploader: s3manager.NewUploaderWithClient(client, func(u *s3manager.Uploader) {
+			if partSizeMB > 0 {
+				u.PartSize = int64(partSizeMB) * 1024 * 1024
+			}
+			if concurrency > 0 {
+				u.Concurrency = concurrency
+			}
+		}),
+		downloader: s3manager.NewDownloaderWithClient(client, func(d *s3manager.Downloader) {
+			if partSizeMB > 0 {
+				d.PartSize = int64(partSizeMB) * 1024 * 1024
+			}
+			if concurrency > 0 {
+				d.Concurrency = concurrency
+			}
+		}),
+		bucket: bucketName,
+
+		logPartSize:        logPartSizeBytes(partSizeMB),
+		logPartConcurrency: logPartConcurrency(concurrency),
+	}
+}
+
+func logPartSizeBytes(partSizeMB int) int64 {
+	if partSizeMB <= 0 {
+		partSizeMB = defaultLogPartSizeMB
+	}
+	return int64(partSizeMB) * 1024 * 1024
+}
+
+func logPartConcurrency(concurrency int) int {
+	if concurrency <= 0 {
+		return defaultLogPartConcurrency
+	}
+	return concurrency
+}
+
+// credsFromQuery builds a *credentials.Credentials from the `provider`,
+// `role_arn`, `external_id` and `session_name` query params. If accessKeyID
+// is non-empty (the static `s3://key:secret@host/...` form) it's used as a
+// base unless overridden by `provider`. If nothing applies, nil is returned
+// and the SDK's default credential chain takes over (env vars, shared
+// config, EC2/ECS metadata, IRSA web identity, in that order), so fn running
+// on EC2/EKS picks up instance or pod credentials automatically.
+func credsFromQuery(q url.Values, accessKeyID, secretAccessKey string) *credentials.Credentials {
+	var base *credentials.Credentials
+	useStatic := true
+	switch q.Get("provider") {
+	case "env":
+		base = credentials.NewEnvCredentials()
+	case "ec2":
+		base = ec2rolecreds.NewCredentials(session.Must(session.NewSession()))
+	case "ecs", "web_identity":
+		// the SDK's default provider chain already walks the ECS container
+		// credentials endpoint and the IRSA/web identity provider, so fall
+		// through and let session.NewSession below pick it up.
+		useStatic = false
+	}
+
+	if base == nil && useStatic && accessKeyID != "" {
+		base = credentials.NewStaticCredentials(accessKeyID, secretAccessKey, "")
+	}
+
+	roleARN := q.Get("role_arn")
+	if roleARN == "" {
+		return base
+	}
+
+	sess := session.Must(session.NewSession(&aws.Config{Credentials: base}))
+	return stscreds.NewCredentials(sess, roleARN, func(p *stscreds.AssumeRoleProvider) {
+		if externalID := q.Get("external_id"); externalID != "" {
+			p.ExternalID = aws.String(externalID)
+		}
+		if sessionName := q.Get("session_name"); sessionName != "" {
+			p.RoleSessionName = sessionName
+		}
+	})
+}
+
+// intQueryParam parses an optional non-negative integer query param,
+// returning def if it's absent.
+func intQueryParam(q url.Values, name string, def int) (int, error) {
+	raw := q.Get(name)
+	if raw == "" {
+		return def, nil
+	}
+	v, err := strconv.Atoi(raw)
+	if err != nil || v < 0 {
+		return 0, fmt.Errorf("%s must be a non-negative integer, got %q", name, raw)
+	}
+	return v, nil
+}
+
+// parseSSE reads the `sse`, `sse-kms-key-id` and `sse-c-key` query params off
+// of q and returns the server-side-encryption mode along with whatever key
+// material goes with it. The customer key is read once here (rather than per
+// request) and kept decoded on the store.
+func parseSSE(q url.Values) (mode, kmsKeyID string, customerKey []byte, customerKeyMD5 string, err error) {
+	mode = q.Get("sse")
+	switch strings.ToLower(mode) {
+	case "":
+		return sseNone, "", nil, "", nil
+	case "aes256", "s3":
+		return sseS3, "", nil, "", nil
+	case "aws:kms", "kms":
+		kmsKeyID = q.Get("sse-kms-key-id")
+		return sseKMS, kmsKeyID, nil, "", nil
+	case "c":
+		encoded := q.Get("sse-c-key")
+		if encoded == "" {
+			return "", "", nil, "", errors.New("sse=c requires a sse-c-key query param with a base64 encoded 256-bit key")
+		}
+		key, err := base64.StdEncoding.DecodeString(encoded)
+		if err != nil {
+			return "", "", nil, "", fmt.Errorf("sse-c-key must be base64 encoded: %v", err)
+		}
+		sum := md5.Sum(key)
+		return sseC, "", key, base64.StdEncoding.EncodeToString(sum[:]), nil
+	default:
+		return "", "", nil, "", fmt.Errorf("unsupported sse mode %q, must be one of AES256, aws:kms, c", mode)
 	}
 }
 
@@ -100,11 +266,59 @@ func New(u *url.URL) (models.LogStore, error) {
 		return nil, errors.New("must provide non-empty bucket name in path of s3 api url. e.g. s3://s3.com/us-east-1/my_bucket")
 	}
 
-	logrus.WithFields(logrus.Fields{"bucketName": bucketName, "region": region, "endpoint": endpoint, "access_key_id": accessKeyID, "useSSL": useSSL}).Info("checking / creating s3 bucket")
-	store := createStore(bucketName, endpoint, region, accessKeyID, secretAccessKey, useSSL)
+	sseMode, kmsKeyID, customerKey, customerKeyMD5, err := parseSSE(u.Query())
+	if err != nil {
+		return nil, err
+	}
+
+	creds := credsFromQuery(u.Query(), accessKeyID, secretAccessKey)
+
+	partSizeMB, err := intQueryParam(u.Query(), "part_size_mb", 0)
+	if err != nil {
+		return nil, err
+	}
+	concurrency, err := intQueryParam(u.Query(), "concurrency", 0)
+	if err != nil {
+		return nil, err
+	}
+	listConcurrency, err := intQueryParam(u.Query(), "list_concurrency", defaultListConcurrency)
+	if err != nil {
+		return nil, err
+	}
+
+	storageClass := u.Query().Get("storage_class")
+	acl := u.Query().Get("acl")
+	retentionDays, err := intQueryParam(u.Query(), "retention_days", 0)
+	if err != nil {
+		return nil, err
+	}
+	objectLock := u.Query().Get("object_lock") == "true"
+
+	var maxPresignTTL time.Duration
+	if raw := u.Query().Get("max_presign_ttl"); raw != "" {
+		maxPresignTTL, err = time.ParseDuration(raw)
+		if err != nil {
+			return nil, fmt.Errorf("max_presign_ttl must be a valid duration, got %q: %v", raw, err)
+		}
+	}
+
+	logrus.WithFields(logrus.Fields{"bucketName": bucketName, "region": region, "endpoint": endpoint, "access_key_id": accessKeyID, "useSSL": useSSL, "sse": sseMode, "provider": u.Query().Get("provider"), "role_arn": u.Query().Get("role_arn"), "storage_class": storageClass, "retention_days": retentionDays, "object_lock": objectLock}).Info("checking / creating s3 bucket")
+	store := createStore(bucketName, endpoint, region, creds, useSSL, partSizeMB, concurrency)
+	store.sseMode = sseMode
+	store.sseKMSKeyID = kmsKeyID
+	store.sseCustomerKey = customerKey
+	store.sseCustomerKeyMD5 = customerKeyMD5
+	store.listConcurrency = listConcurrency
+	store.storageClass = storageClass
+	store.acl = acl
+	store.maxPresignTTL = maxPresignTTL
 
 	// ensure the bucket exists, creating if it does not
-	_, err := store.client.CreateBucket(&s3.CreateBucketInput{Bucket: aws.String(bucketName)})
+	createBucketInput := &s3.CreateBucketInput{Bucket: aws.String(bucketName)}
+	if objectLock {
+		createBucketInput.ObjectLockEnabledForBucket = aws.Bool(true)
+	}
+	_, err = store.client.CreateBucket(createBucketInput)
 	if err != nil {
 		if aerr, ok := err.(awserr.Error); ok {
 			switch aerr.Code() {
@@ -118,9 +332,72 @@ func New(u *url.URL) (models.LogStore, error) {
 		}
 	}
 
+	if retentionDays > 0 {
+		if err := store.applyLifecycle(bucketName, retentionDays); err != nil {
+			return nil, err
+		}
+	}
+
+	if objectLock {
+		if retentionDays <= 0 {
+			retentionDays = defaultObjectLockRetentionDays
+		}
+		if err := store.applyObjectLockRetention(bucketName, retentionDays); err != nil {
+			return nil, err
+		}
+	}
+
 	return store, nil
 }
 
+// applyLifecycle expires log and call objects after retentionDays so
+// operators can bound storage cost/retention without managing bucket policy
+// out of band. Our keys don't share a single logPath/callPath prefix (they're
+// namespaced per-app), so the rule is applied bucket-wide.
+func (s *store) applyLifecycle(bucketName string, retentionDays int) error {
+	_, err := s.client.PutBucketLifecycleConfiguration(&s3.PutBucketLifecycleConfigurationInput{
+		Bucket: aws.String(bucketName),
+		LifecycleConfiguration: &s3.BucketLifecycleConfiguration{
+			Rules: []*s3.LifecycleRule{
+				{
+					ID:     aws.String("fn-log-retention"),
+					Status: aws.String(s3.ExpirationStatusEnabled),
+					Filter: &s3.LifecycleRuleFilter{Prefix: aws.String("")},
+					Expiration: &s3.LifecycleExpiration{
+						Days: aws.Int64(int64(retentionDays)),
+					},
+				},
+			},
+		},
+	})
+	if err != nil {
+		return fmt.Errorf("failed to set bucket lifecycle: %v", err)
+	}
+	return nil
+}
+
+// applyObjectLockRetention sets a default Object Lock retention period on
+// the bucket so log and call objects become tamper-evident for
+// retentionDays after upload.
+func (s *store) applyObjectLockRetention(bucketName string, retentionDays int) error {
+	_, err := s.client.PutObjectLockConfiguration(&s3.PutObjectLockConfigurationInput{
+		Bucket: aws.String(bucketName),
+		ObjectLockConfiguration: &s3.ObjectLockConfiguration{
+			ObjectLockEnabled: aws.String(s3.ObjectLockEnabledEnabled),
+			Rule: &s3.ObjectLockRule{
+				DefaultRetention: &s3.DefaultRetention{
+					Mode: aws.String(s3.ObjectLockRetentionModeGovernance),
+					Days: aws.Int64(int64(retentionDays)),
+				},
+			},
+		},
+	})
+	if err != nil {
+		return fmt.Errorf("failed to set object lock configuration: %v", err)
+	}
+	return nil
+}
+
 func logPath(appName, callID string) string {
 	// raw url encode, b/c s3 does not like: & $ @ = : ; + , ?
 	appName = base64.RawURLEncoding.EncodeToString([]byte(appName)) // TODO optimize..
@@ -140,6 +417,8 @@ func (s *store) InsertLog(ctx context.Context, appID, callID string, callLog io.
 		Body:        cr,
 		ContentType: aws.String(contentType),
 	}
+	s.addSSEToUpload(params)
+	s.addStorageOptsToUpload(params)
 
 	logrus.WithFields(logrus.Fields{"bucketName": s.bucket, "key": objectName}).Debug("Uploading log")
 	_, err := s.uploader.UploadWithContext(ctx, params)
@@ -151,19 +430,70 @@ func (s *store) InsertLog(ctx context.Context, appID, callID string, callLog io.
 	return nil
 }
 
+// GetLog returns the full log for a call. It's a thin wrapper around
+// GetLogRange that fetches the whole object, wrapped so the underlying S3
+// response body gets closed once it's fully read -- GetLog's signature
+// predates GetLogRange and only promises callers an io.Reader, so they
+// won't know to Close() it themselves.
 func (s *store) GetLog(ctx context.Context, appID, callID string) (io.Reader, error) {
-	ctx, span := trace.StartSpan(ctx, "s3_get_log")
+	rc, err := s.GetLogRange(ctx, appID, callID, 0, 0)
+	if err != nil {
+		return nil, err
+	}
+	return &closeOnEOFReader{rc: rc}, nil
+}
+
+// closeOnEOFReader closes the wrapped ReadCloser as soon as a Read off of it
+// returns an error (io.EOF included), so a caller that only ever reads until
+// EOF -- the only contract GetLog's io.Reader return type promises -- closes
+// the underlying connection without having to know it's there.
+type closeOnEOFReader struct {
+	rc io.ReadCloser
+}
+
+func (r *closeOnEOFReader) Read(p []byte) (int, error) {
+	n, err := r.rc.Read(p)
+	if err != nil {
+		r.rc.Close()
+	}
+	return n, err
+}
+
+// GetLogRange returns a reader for the bytes of the log for a call starting
+// at offset and spanning length bytes, or the whole log if length <= 0. A
+// caller-specified range is fetched with a single ranged GetObject; a
+// whole-log read -- the actual "large log" case `part_size_mb`/
+// `concurrency` are meant to tune -- is split into part-sized ranged gets
+// fanned out across `concurrency` workers, see streamWholeLog. Either way
+// the returned reader streams rather than buffering the whole object in
+// memory.
+func (s *store) GetLogRange(ctx context.Context, appID, callID string, offset, length int64) (io.ReadCloser, error) {
+	ctx, span := trace.StartSpan(ctx, "s3_get_log_range")
 	defer span.End()
 
 	objectName := logPath(appID, callID)
-	logrus.WithFields(logrus.Fields{"bucketName": s.bucket, "key": objectName}).Debug("Downloading log")
 
-	// stream the logs to an in-memory buffer
-	target := &aws.WriteAtBuffer{}
-	size, err := s.downloader.DownloadWithContext(ctx, target, &s3.GetObjectInput{
+	logrus.WithFields(logrus.Fields{"bucketName": s.bucket, "key": objectName, "offset": offset, "length": length}).Debug("Downloading log")
+
+	if length > 0 {
+		return s.getLogPart(ctx, objectName, offset, length)
+	}
+	return s.streamWholeLog(ctx, objectName)
+}
+
+// getLogPart issues a single ranged (or, if length <= 0, unranged) GetObject
+// against objectName and returns the response body directly.
+func (s *store) getLogPart(ctx context.Context, objectName string, offset, length int64) (io.ReadCloser, error) {
+	getInput := &s3.GetObjectInput{
 		Bucket: aws.String(s.bucket),
 		Key:    aws.String(objectName),
-	})
+	}
+	if length > 0 {
+		getInput.Range = aws.String(fmt.Sprintf("bytes=%d-%d", offset, offset+length-1))
+	}
+	s.addSSEToGet(getInput)
+
+	resp, err := s.client.GetObjectWithContext(ctx, getInput)
 	if err != nil {
 		aerr, ok := err.(awserr.Error)
 		if ok && aerr.Code() == s3.ErrCodeNoSuchKey {
@@ -172,8 +502,198 @@ func (s *store) GetLog(ctx context.Context, appID, callID string) (io.Reader, er
 		return nil, fmt.Errorf("failed to read log, %v", err)
 	}
 
-	stats.Record(ctx, downloadSizeMeasure.M(size))
-	return bytes.NewReader(target.Bytes()), nil
+	if resp.ContentLength != nil {
+		stats.Record(ctx, downloadSizeMeasure.M(*resp.ContentLength))
+	}
+	return resp.Body, nil
+}
+
+// streamWholeLog fetches the full log object as a sequence of logPartSize
+// ranged gets, fanned out across logPartConcurrency workers, reassembled in
+// order and streamed out through an io.Pipe -- so a large log is fetched in
+// parallel part-sized chunks (what `part_size_mb`/`concurrency` configure)
+// while memory use stays bounded to roughly partSize*concurrency rather
+// than the whole object.
+func (s *store) streamWholeLog(ctx context.Context, objectName string) (io.ReadCloser, error) {
+	head, err := s.client.HeadObjectWithContext(ctx, &s3.HeadObjectInput{
+		Bucket: aws.String(s.bucket),
+		Key:    aws.String(objectName),
+	})
+	if err != nil {
+		aerr, ok := err.(awserr.Error)
+		if ok && (aerr.Code() == s3.ErrCodeNoSuchKey || aerr.Code() == "NotFound") {
+			return nil, models.ErrCallLogNotFound
+		}
+		return nil, fmt.Errorf("failed to read log, %v", err)
+	}
+	size := aws.Int64Value(head.ContentLength)
+
+	if size <= s.logPartSize {
+		return s.getLogPart(ctx, objectName, 0, 0)
+	}
+
+	numParts := int((size + s.logPartSize - 1) / s.logPartSize)
+	concurrency := s.logPartConcurrency
+	if concurrency > numParts {
+		concurrency = numParts
+	}
+
+	type part struct {
+		idx   int
+		bytes []byte
+	}
+
+	ctx, cancel := context.WithCancel(ctx)
+
+	jobCh := make(chan int)
+	resCh := make(chan part, numParts)
+	errCh := make(chan error, 1)
+	// one slot per part that's been dispatched but not yet written to the
+	// pipe, so at most `concurrency` parts are held in memory at once.
+	slots := make(chan struct{}, concurrency)
+
+	var wg sync.WaitGroup
+	wg.Add(concurrency)
+	for w := 0; w < concurrency; w++ {
+		go func() {
+			defer wg.Done()
+			for idx := range jobCh {
+				start := int64(idx) * s.logPartSize
+				end := start + s.logPartSize
+				if end > size {
+					end = size
+				}
+
+				rc, err := s.getLogPart(ctx, objectName, start, end-start)
+				if err != nil {
+					select {
+					case errCh <- err:
+					default:
+					}
+					cancel()
+					return
+				}
+				buf, err := ioutil.ReadAll(rc)
+				rc.Close()
+				if err != nil {
+					select {
+					case errCh <- err:
+					default:
+					}
+					cancel()
+					return
+				}
+				resCh <- part{idx: idx, bytes: buf}
+			}
+		}()
+	}
+
+	go func() {
+		defer close(jobCh)
+		for i := 0; i < numParts; i++ {
+			select {
+			case slots <- struct{}{}:
+			case <-ctx.Done():
+				return
+			}
+			select {
+			case jobCh <- i:
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+
+	go func() {
+		wg.Wait()
+		close(resCh)
+	}()
+
+	pr, pw := io.Pipe()
+	go func() {
+		defer cancel()
+		pending := make(map[int][]byte, concurrency)
+		next := 0
+		for next < numParts {
+			if buf, ok := pending[next]; ok {
+				delete(pending, next)
+				if _, err := pw.Write(buf); err != nil {
+					pw.CloseWithError(err)
+					return
+				}
+				<-slots
+				next++
+				continue
+			}
+
+			select {
+			case p, ok := <-resCh:
+				if !ok {
+					select {
+					case err := <-errCh:
+						pw.CloseWithError(err)
+					default:
+						pw.CloseWithError(errors.New("failed to read log: a download worker exited early"))
+					}
+					return
+				}
+				pending[p.idx] = p.bytes
+			case <-ctx.Done():
+				pw.CloseWithError(ctx.Err())
+				return
+			}
+		}
+		pw.Close()
+	}()
+
+	return pr, nil
+}
+
+// PresignGetLog returns a pre-signed URL for the log's GetObject request,
+// valid for ttl, so clients can stream large logs directly from S3 instead
+// of proxying through the fn API server. ttl is capped at `max_presign_ttl`
+// if the store was configured with one.
+//
+// Unsupported when the store is configured with sse=c: a presigned URL
+// would need the caller to resend the SSECustomerKey/MD5 headers baked in
+// at sign time, which means they'd already need the server's secret
+// customer key, defeating the whole point of letting clients fetch
+// directly from S3.
+//
+// TODO this is only the store-layer half of the feature: nothing yet calls
+// PresignGetLog. A follow-up still needs to wire it into the HTTP API layer
+// (GET /v1/apps/:app/calls/:call/log?presign=true), with a
+// models.ErrNotImplemented fallback for non-s3 log stores -- that lives in
+// the api/server package, which isn't part of this tree. Don't consider the
+// presigned-log-url backlog item done until that lands.
+func (s *store) PresignGetLog(ctx context.Context, appID, callID string, ttl time.Duration) (string, error) {
+	ctx, span := trace.StartSpan(ctx, "s3_presign_get_log")
+	defer span.End()
+
+	if s.sseMode == sseC {
+		return "", errors.New("cannot presign a log url for a store configured with sse=c: the customer key can't be safely embedded in a pre-signed URL")
+	}
+
+	if s.maxPresignTTL > 0 && ttl > s.maxPresignTTL {
+		ttl = s.maxPresignTTL
+	}
+
+	objectName := logPath(appID, callID)
+	getInput := &s3.GetObjectInput{
+		Bucket: aws.String(s.bucket),
+		Key:    aws.String(objectName),
+	}
+	s.addSSEToGet(getInput)
+
+	req, _ := s.client.GetObjectRequest(getInput)
+	req.SetContext(ctx)
+	url, err := req.Presign(ttl)
+	if err != nil {
+		return "", fmt.Errorf("failed to presign log url, %v", err)
+	}
+
+	logrus.WithFields(logrus.Fields{"bucketName": s.bucket, "key": objectName, "ttl": ttl}).Debug("Presigned log url")
+	return url, nil
 }
 
 func callPath(appName, callID string) string {
@@ -199,6 +719,8 @@ func (s *store) InsertCall(ctx context.Context, call *models.Call) error {
 		Body:        bytes.NewReader(byts),
 		ContentType: aws.String(contentType),
 	}
+	s.addSSEToUpload(params)
+	s.addStorageOptsToUpload(params)
 
 	logrus.WithFields(logrus.Fields{"bucketName": s.bucket, "key": objectName}).Debug("Uploading call")
 	_, err = s.uploader.UploadWithContext(ctx, params)
@@ -206,6 +728,79 @@ func (s *store) InsertCall(ctx context.Context, call *models.Call) error {
 		return fmt.Errorf("failed to write log, %v", err)
 	}
 
+	// also write a compact secondary-index entry so GetCalls can list and
+	// filter without a GetCall per key, see indexKey.
+	if err := s.insertCallIndex(ctx, call); err != nil {
+		return err
+	}
+
+	// dual-write the pre-index marker keys too, for one release's worth of
+	// migration: GetCalls falls back to listing these when an index entry
+	// is missing, so a call written by a binary that predates the idx/
+	// scheme doesn't silently drop out of listings. If the index write
+	// above fails, InsertCall returns its error before these are written. Safe to remove once every call
+	// in a bucket is guaranteed to have an index entry.
+	if err := s.insertLegacyCallMarkers(ctx, call); err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// insertLegacyCallMarkers writes the empty marker objects GetCalls falls
+// back to listing when it can't find an idx/ entry for a call, see
+// callMarkerKey/callKey.
+func (s *store) insertLegacyCallMarkers(ctx context.Context, call *models.Call) error {
+	for _, key := range []string{callKey(call.AppID, call.ID), callMarkerKey(call.AppID, call.Path, call.ID)} {
+		params := &s3manager.UploadInput{
+			Bucket:      aws.String(s.bucket),
+			Key:         aws.String(key),
+			Body:        bytes.NewReader(nil),
+			ContentType: aws.String(contentType),
+		}
+		s.addSSEToUpload(params)
+		s.addStorageOptsToUpload(params)
+
+		if _, err := s.uploader.UploadWithContext(ctx, params); err != nil {
+			return fmt.Errorf("failed to write legacy call marker, %v", err)
+		}
+	}
+	return nil
+}
+
+// insertCallIndex writes the small summary blob GetCalls lists and filters
+// against, keyed by the hour the call was created and the (flipped) call
+// id.
+func (s *store) insertCallIndex(ctx context.Context, call *models.Call) error {
+	entry := callIndexEntry{
+		ID:          call.ID,
+		Path:        call.Path,
+		Status:      call.Status,
+		CreatedAt:   call.CreatedAt,
+		CompletedAt: call.CompletedAt,
+	}
+
+	byts, err := json.Marshal(entry)
+	if err != nil {
+		return err
+	}
+
+	objectName := indexKey(call.AppID, call.CreatedAt, call.ID)
+	params := &s3manager.UploadInput{
+		Bucket:      aws.String(s.bucket),
+		Key:         aws.String(objectName),
+		Body:        bytes.NewReader(byts),
+		ContentType: aws.String(contentType),
+	}
+	s.addSSEToUpload(params)
+	s.addStorageOptsToUpload(params)
+
+	logrus.WithFields(logrus.Fields{"bucketName": s.bucket, "key": objectName}).Debug("Uploading call index entry")
+	_, err = s.uploader.UploadWithContext(ctx, params)
+	if err != nil {
+		return fmt.Errorf("failed to write call index, %v", err)
+	}
+
 	return nil
 }
 
@@ -217,12 +812,15 @@ func (s *store) GetCall(ctx context.Context, appName, callID string) (*models.Ca
 	objectName := callPath(appName, callID)
 	logrus.WithFields(logrus.Fields{"bucketName": s.bucket, "key": objectName}).Debug("Downloading call")
 
-	// stream the logs to an in-memory buffer
-	var target aws.WriteAtBuffer
-	_, err := s.downloader.DownloadWithContext(ctx, &target, &s3.GetObjectInput{
+	getInput := &s3.GetObjectInput{
 		Bucket: aws.String(s.bucket),
 		Key:    aws.String(objectName),
-	})
+	}
+	s.addSSEToGet(getInput)
+
+	// stream the logs to an in-memory buffer
+	var target aws.WriteAtBuffer
+	_, err := s.downloader.DownloadWithContext(ctx, &target, getInput)
 	if err != nil {
 		aerr, ok := err.(awserr.Error)
 		if ok && aerr.Code() == s3.ErrCodeNoSuchKey {
@@ -240,6 +838,46 @@ func (s *store) GetCall(ctx context.Context, appName, callID string) (*models.Ca
 	return &call, nil
 }
 
+// addSSEToUpload sets whichever server-side-encryption fields apply to this
+// store on an outgoing upload, per parseSSE.
+func (s *store) addSSEToUpload(params *s3manager.UploadInput) {
+	switch s.sseMode {
+	case sseS3:
+		params.ServerSideEncryption = aws.String(s3.ServerSideEncryptionAes256)
+	case sseKMS:
+		params.ServerSideEncryption = aws.String(s3.ServerSideEncryptionAwsKms)
+		if s.sseKMSKeyID != "" {
+			params.SSEKMSKeyId = aws.String(s.sseKMSKeyID)
+		}
+	case sseC:
+		params.SSECustomerAlgorithm = aws.String(s3.ServerSideEncryptionAes256)
+		params.SSECustomerKey = aws.String(string(s.sseCustomerKey))
+		params.SSECustomerKeyMD5 = aws.String(s.sseCustomerKeyMD5)
+	}
+}
+
+// addSSEToGet attaches the matching SSECustomer* fields to a GetObjectInput,
+// required when the object was uploaded with SSE-C.
+func (s *store) addSSEToGet(params *s3.GetObjectInput) {
+	if s.sseMode != sseC {
+		return
+	}
+	params.SSECustomerAlgorithm = aws.String(s3.ServerSideEncryptionAes256)
+	params.SSECustomerKey = aws.String(string(s.sseCustomerKey))
+	params.SSECustomerKeyMD5 = aws.String(s.sseCustomerKeyMD5)
+}
+
+// addStorageOptsToUpload sets the configured storage class and ACL, if any,
+// on an outgoing upload, see `storage_class` and `acl` query params.
+func (s *store) addStorageOptsToUpload(params *s3manager.UploadInput) {
+	if s.storageClass != "" {
+		params.StorageClass = aws.String(s.storageClass)
+	}
+	if s.acl != "" {
+		params.ACL = aws.String(s.acl)
+	}
+}
+
 func xorCursor(oid string) string {
 	// 01C860Z3M9A7WHJ00000000000
 	cp := []byte(oid)
@@ -251,90 +889,380 @@ func xorCursor(oid string) string {
 	return string(cp[:])
 }
 
+// crockfordAlphabet is the base32 alphabet a ULID's first 10 characters are
+// encoded in (see ulidBucket).
+const crockfordAlphabet = "0123456789ABCDEFGHJKMNPQRSTVWXYZ"
+
+// ulidBucket decodes the 48-bit millisecond timestamp embedded in a
+// ULID-formatted call id's first 10 characters and returns the same
+// hour-bucket string indexKey would have filed it under, so GetCalls can
+// tell which bucket a cursor belongs to without having stored it anywhere.
+func ulidBucket(id string) (string, error) {
+	if len(id) < 10 {
+		return "", fmt.Errorf("id %q is too short to be a ulid", id)
+	}
+
+	var ms uint64
+	for i := 0; i < 10; i++ {
+		idx := strings.IndexByte(crockfordAlphabet, byte(strings.ToUpper(string(id[i]))[0]))
+		if idx < 0 {
+			return "", fmt.Errorf("id %q is not a valid ulid", id)
+		}
+		ms = ms<<5 | uint64(idx)
+	}
+
+	t := time.Unix(0, int64(ms)*int64(time.Millisecond)).UTC()
+	return t.Format("2006010215"), nil
+}
+
+// callMarkerKey is the pre-idx/ per-path marker key for a call, still
+// dual-written by insertLegacyCallMarkers so GetCalls can fall back to it.
 func callMarkerKey(app, path, id string) string {
-	// TODO
 	return "m:" + app + ":" + path + ":" + id
 }
 
+// callKey is the pre-idx/ per-app marker key for a call, still
+// dual-written by insertLegacyCallMarkers so GetCalls can fall back to it.
 func callKey(app, id string) string {
-	// TODO
 	return "s:" + app + ":" + id
 }
 
+// callIndexEntry is the compact secondary-index record we write alongside
+// every call object, so GetCalls can list and filter without issuing a
+// GetCall per key.
+type callIndexEntry struct {
+	ID          string          `json:"id"`
+	Path        string          `json:"path"`
+	Status      string          `json:"status"`
+	CreatedAt   common.DateTime `json:"created_at"`
+	CompletedAt common.DateTime `json:"completed_at"`
+}
+
+// indexKey returns the key for a call's secondary-index entry. Entries are
+// bucketed by the hour the call was created so GetCalls can narrow its
+// listing to a filter's from_time/to_time window instead of scanning every
+// call an app has ever made.
+func indexKey(appID string, createdAt common.DateTime, callID string) string {
+	bucket := time.Time(createdAt).UTC().Format("2006010215")
+	return "idx/" + appID + "/" + bucket + "/" + xorCursor(callID)
+}
+
+// indexPrefix is one prefix GetCalls lists under, along with the hour
+// bucket it covers. bucket is empty for the unbounded root prefix, which
+// spans every bucket an app has (see indexPrefixes).
+type indexPrefix struct {
+	prefix string
+	bucket string
+}
+
+// indexPrefixes returns the hour-bucket prefixes GetCalls should list under
+// to cover filter's from_time/to_time window. With neither bound set, it
+// falls back to the index root for the app (every bucket, oldest to
+// newest); with only one bound set, the other defaults to now/30 days back
+// so an open-ended filter doesn't force a scan since the dawn of time.
+func indexPrefixes(filter *models.CallFilter) []indexPrefix {
+	root := "idx/" + filter.AppName + "/"
+
+	from := time.Time(filter.FromTime)
+	to := time.Time(filter.ToTime)
+	if from.IsZero() && to.IsZero() {
+		return []indexPrefix{{prefix: root}}
+	}
+	if to.IsZero() {
+		to = time.Now().UTC()
+	}
+	if from.IsZero() {
+		from = to.Add(-30 * 24 * time.Hour)
+	}
+
+	var prefixes []indexPrefix
+	for t := from.UTC().Truncate(time.Hour); !t.After(to); t = t.Add(time.Hour) {
+		bucket := t.Format("2006010215")
+		prefixes = append(prefixes, indexPrefix{prefix: root + bucket + "/", bucket: bucket})
+	}
+	return prefixes
+}
+
 // GetCalls returns a list of calls that satisfy the given CallFilter. If no
 // calls exist, an empty list and a nil error are returned.
 func (s *store) GetCalls(ctx context.Context, filter *models.CallFilter) ([]*models.Call, error) {
 	ctx, span := trace.StartSpan(ctx, "s3_get_calls")
 	defer span.End()
 
-	// NOTE:
-	// if filter.Path != ""
-	//   find marker from marker keys, start there, list keys, get next marker from there
-	// else
-	//   use marker for keys
+	// filter.Cursor is a call id, translate to our key format. cursorBucket
+	// is the hour bucket that id's entry was actually filed under (derived
+	// from the id itself, see ulidBucket) -- the marker below is only valid
+	// against a listing of that exact bucket; applying it to an unrelated
+	// bucket would filter out real entries there by coincidence, see below.
+	var marker, cursorBucket string
+	if filter.Cursor != "" {
+		marker = xorCursor(filter.Cursor)
+		cursorBucket, _ = ulidBucket(filter.Cursor)
+	}
+
+	var matched []callIndexEntry
+	for _, ip := range indexPrefixes(filter) {
+		if len(matched) >= filter.PerPage {
+			break
+		}
+
+		prefixMarker := marker
+		if marker != "" && cursorBucket != "" {
+			switch {
+			case ip.bucket == "":
+				// the unbounded root prefix spans every bucket in one flat
+				// listing, so the marker needs the bucket folded in to
+				// resume at the right spot within it.
+				prefixMarker = cursorBucket + "/" + marker
+			case ip.bucket < cursorBucket:
+				// strictly older than the cursor's bucket: already fully
+				// returned on an earlier page, nothing left to list here.
+				continue
+			case ip.bucket > cursorBucket:
+				// newer than the cursor's bucket: untouched by previous
+				// pages, list it from the start rather than filtering by a
+				// marker that belongs to a different bucket entirely.
+				prefixMarker = ""
+			}
+		}
+
+		entries, err := s.listIndexEntries(ctx, ip.prefix, prefixMarker, filter, filter.PerPage-len(matched))
+		if err != nil {
+			return nil, err
+		}
+		matched = append(matched, entries...)
+	}
+
+	// fall back to the pre-idx/ marker keys for anything the index listing
+	// above didn't turn up -- a call written before this release (or by a
+	// code path that skipped insertCallIndex) has no idx/ entry but still
+	// has these, see insertLegacyCallMarkers. Best-effort only: the legacy
+	// keys don't carry Status, so a Status filter isn't applied to them.
+	if len(matched) < filter.PerPage {
+		seen := make(map[string]bool, len(matched))
+		for _, e := range matched {
+			seen[e.ID] = true
+		}
+
+		legacyIDs, err := s.legacyCallIDs(ctx, filter, seen, filter.PerPage-len(matched))
+		if err != nil {
+			return nil, err
+		}
+		for _, id := range legacyIDs {
+			matched = append(matched, callIndexEntry{ID: id})
+		}
+	}
+
+	return s.getCallsForEntries(ctx, filter.AppName, matched)
+}
+
+// listIndexEntries pages through ListObjectsV2 (v1 listing is deprecated on
+// some S3 implementations) under prefix, decoding each small index blob and
+// applying filter's Path/Status predicates as it goes, until want matching
+// entries have been collected or the prefix is exhausted. Filtering inside
+// the paging loop (rather than after it returns) matters: a filtered page
+// can contain far fewer matches than raw objects, and we need to keep
+// pulling further pages from the same prefix to make up the difference
+// instead of stopping at want raw objects and silently under-returning.
+func (s *store) listIndexEntries(ctx context.Context, prefix, marker string, filter *models.CallFilter, want int) ([]callIndexEntry, error) {
+	var entries []callIndexEntry
+	var continuationToken *string
+	for len(entries) < want {
+		input := &s3.ListObjectsV2Input{
+			Bucket: aws.String(s.bucket),
+			Prefix: aws.String(prefix),
+		}
+		if continuationToken != nil {
+			input.ContinuationToken = continuationToken
+		} else if marker != "" {
+			input.StartAfter = aws.String(prefix + marker)
+		}
+
+		result, err := s.client.ListObjectsV2WithContext(ctx, input)
+		if err != nil {
+			return nil, fmt.Errorf("failed to list call index: %v", err)
+		}
 
-	// NOTE we need marker keys to support (app is REQUIRED):
-	// 1) quick iteration per path
-	// 2) sorted by id across all path
-	// marker key: m : {app} : {path} : {id}
-	// key: s: {app} : {id}
+		for _, obj := range result.Contents {
+			getInput := &s3.GetObjectInput{
+				Bucket: aws.String(s.bucket),
+				Key:    obj.Key,
+			}
+			s.addSSEToGet(getInput)
+
+			resp, err := s.client.GetObjectWithContext(ctx, getInput)
+			if err != nil {
+				common.Logger(ctx).WithError(err).WithField("key", *obj.Key).Error("error reading call index entry")
+				continue
+			}
+
+			var entry callIndexEntry
+			err = json.NewDecoder(resp.Body).Decode(&entry)
+			resp.Body.Close()
+			if err != nil {
+				common.Logger(ctx).WithError(err).WithField("key", *obj.Key).Error("error decoding call index entry")
+				continue
+			}
+
+			if filter.Path != "" && entry.Path != filter.Path {
+				continue
+			}
+			if filter.Status != "" && entry.Status != filter.Status {
+				continue
+			}
+			entries = append(entries, entry)
+			if len(entries) >= want {
+				break
+			}
+		}
 
-	// TODO id we need to flip the bits to get DESC order
-	// TODO we need to use first 48 bits of id to approximate created_at
+		if result.IsTruncated == nil || !*result.IsTruncated {
+			break
+		}
+		continuationToken = result.NextContinuationToken
+	}
+	return entries, nil
+}
 
-	prefix := "s:" + filter.AppName
+// legacyCallIDs lists call ids under the pre-idx/ marker schema
+// (callKey/callMarkerKey) that aren't already in seen, for GetCalls to fall
+// back on when the idx/ listing doesn't turn up enough matches.
+func (s *store) legacyCallIDs(ctx context.Context, filter *models.CallFilter, seen map[string]bool, want int) ([]string, error) {
+	prefix := "s:" + filter.AppName + ":"
+	idField := 2
 	if filter.Path != "" {
-		prefix = "m:" + filter.AppName + ":" + filter.Path
+		prefix = "m:" + filter.AppName + ":" + filter.Path + ":"
+		idField = 3
 	}
 
-	// filter.Cursor is a call id, translate to our key format. if a path is
-	// provided, we list keys from markers instead.
+	// callKey/callMarkerKey store the raw (unflipped) call id, unlike
+	// idx/'s xorCursor(id) cursors, so the marker here is the cursor as-is.
 	var marker string
 	if filter.Cursor != "" {
-		cursor := xorCursor(filter.Cursor)
-		marker = "s:" + filter.AppName + cursor
-		if filter.Path != "" {
-			marker = "m:" + filter.AppName + ":" + filter.Path + ":" + cursor
+		marker = prefix + filter.Cursor
+	}
+
+	var ids []string
+	var continuationToken *string
+	for len(ids) < want {
+		input := &s3.ListObjectsV2Input{
+			Bucket: aws.String(s.bucket),
+			Prefix: aws.String(prefix),
 		}
+		if continuationToken != nil {
+			input.ContinuationToken = continuationToken
+		} else if marker != "" {
+			input.StartAfter = aws.String(marker)
+		}
+
+		result, err := s.client.ListObjectsV2WithContext(ctx, input)
+		if err != nil {
+			return nil, fmt.Errorf("failed to list legacy call markers: %v", err)
+		}
+
+		for _, obj := range result.Contents {
+			fields := strings.Split(*obj.Key, ":")
+			if len(fields) <= idField {
+				continue
+			}
+			id := fields[idField]
+			if seen[id] {
+				continue
+			}
+			seen[id] = true
+			ids = append(ids, id)
+			if len(ids) >= want {
+				break
+			}
+		}
+
+		if result.IsTruncated == nil || !*result.IsTruncated {
+			break
+		}
+		continuationToken = result.NextContinuationToken
 	}
+	return ids, nil
+}
 
-	input := &s3.ListObjectsInput{
-		Bucket:  aws.String(s.bucket),
-		MaxKeys: aws.Int64(int64(filter.PerPage)),
-		Marker:  aws.String(marker),
-		Prefix:  aws.String(prefix),
+// getCallsForEntries fans out a bounded pool of GetCall workers over the
+// index entries that survived filtering, preserving their order, and keeps
+// going past per-call errors (e.g. a call that was deleted between the list
+// and the get) the same way the old serial loop did.
+func (s *store) getCallsForEntries(ctx context.Context, appName string, entries []callIndexEntry) ([]*models.Call, error) {
+	if len(entries) == 0 {
+		return []*models.Call{}, nil
 	}
 
-	result, err := s.client.ListObjects(input)
-	if err != nil {
-		return nil, fmt.Errorf("failed to list logs: %v", err)
+	type job struct {
+		idx      int
+		app, id  string
+	}
+	type result struct {
+		idx  int
+		call *models.Call
 	}
 
-	calls := make([]*models.Call, 0, len(result.Contents))
+	jobs := make([]job, len(entries))
+	for i, e := range entries {
+		jobs[i] = job{idx: i, app: appName, id: e.ID}
+	}
 
-	for _, obj := range result.Contents {
-		var app, id string
-		if filter.Path != "" {
-			fields := strings.Split(*obj.Key, ":")
-			// XXX(reed): validate
-			app = fields[1]
-			id = fields[3]
-		} else {
-			fields := strings.Split(*obj.Key, ":")
-			// XXX(reed): validate
-			app = fields[1]
-			id = fields[2]
-		}
+	concurrency := s.listConcurrency
+	if concurrency <= 0 {
+		concurrency = defaultListConcurrency
+	}
+	if concurrency > len(jobs) {
+		concurrency = len(jobs)
+	}
 
-		// NOTE: s3 doesn't have a way to get multiple objects so just use GetCall
-		// TODO we should reuse the buffer to decode these
-		call, err := s.GetCall(ctx, app, id)
-		if err != nil {
-			common.Logger(ctx).WithError(err).WithFields(logrus.Fields{"app": app, "id": id}).Error("error filling call object")
-			continue
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	jobCh := make(chan job)
+	resCh := make(chan result, len(jobs))
+
+	var wg sync.WaitGroup
+	wg.Add(concurrency)
+	for w := 0; w < concurrency; w++ {
+		go func() {
+			defer wg.Done()
+			for j := range jobCh {
+				call, err := s.GetCall(ctx, j.app, j.id)
+				if err != nil {
+					common.Logger(ctx).WithError(err).WithFields(logrus.Fields{"app": j.app, "id": j.id}).Error("error filling call object")
+					continue
+				}
+				resCh <- result{idx: j.idx, call: call}
+			}
+		}()
+	}
+
+	go func() {
+		defer close(jobCh)
+		for _, j := range jobs {
+			select {
+			case jobCh <- j:
+			case <-ctx.Done():
+				return
+			}
 		}
+	}()
 
-		calls = append(calls, call)
+	go func() {
+		wg.Wait()
+		close(resCh)
+	}()
+
+	ordered := make([]*models.Call, len(jobs))
+	for r := range resCh {
+		ordered[r.idx] = r.call
+	}
+
+	calls := make([]*models.Call, 0, len(ordered))
+	for _, c := range ordered {
+		if c != nil {
+			calls = append(calls, c)
+		}
 	}
 
 	return calls, nil
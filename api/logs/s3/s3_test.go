@@ -0,0 +1,197 @@
+package s3
+
+import (
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/fnproject/fn/api/common"
+	"github.com/fnproject/fn/api/models"
+)
+
+func mustParseQuery(t *testing.T, raw string) url.Values {
+	t.Helper()
+	q, err := url.ParseQuery(raw)
+	if err != nil {
+		t.Fatalf("failed to parse query %q: %v", raw, err)
+	}
+	return q
+}
+
+func TestParseSSE(t *testing.T) {
+	cases := []struct {
+		name       string
+		query      string
+		wantMode   string
+		wantKMSKey string
+		wantErr    bool
+	}{
+		{name: "none", query: "", wantMode: sseNone},
+		{name: "s3 aes256", query: "sse=AES256", wantMode: sseS3},
+		{name: "s3 lowercase alias", query: "sse=s3", wantMode: sseS3},
+		{name: "kms without key", query: "sse=aws:kms", wantMode: sseKMS},
+		{name: "kms with key", query: "sse=kms&sse-kms-key-id=my-key", wantMode: sseKMS, wantKMSKey: "my-key"},
+		{name: "customer key missing", query: "sse=c", wantErr: true},
+		{name: "customer key not base64", query: "sse=c&sse-c-key=not-base64!!", wantErr: true},
+		{name: "customer key ok", query: "sse=c&sse-c-key=MDEyMzQ1Njc4OTAxMjM0NTY3ODkwMTIzNDU2Nzg5MDE=", wantMode: sseC},
+		{name: "unsupported mode", query: "sse=rot13", wantErr: true},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			mode, kmsKeyID, customerKey, customerKeyMD5, err := parseSSE(mustParseQuery(t, tc.query))
+			if tc.wantErr {
+				if err == nil {
+					t.Fatalf("expected an error, got none")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if mode != tc.wantMode {
+				t.Errorf("mode = %q, want %q", mode, tc.wantMode)
+			}
+			if kmsKeyID != tc.wantKMSKey {
+				t.Errorf("kmsKeyID = %q, want %q", kmsKeyID, tc.wantKMSKey)
+			}
+			if mode == sseC {
+				if len(customerKey) == 0 {
+					t.Errorf("expected a decoded customer key, got none")
+				}
+				if customerKeyMD5 == "" {
+					t.Errorf("expected a customer key md5, got none")
+				}
+			}
+		})
+	}
+}
+
+func TestCredsFromQuery(t *testing.T) {
+	// no provider, no static keys and no role_arn: falls through to the SDK
+	// default chain, i.e. a nil *credentials.Credentials.
+	if creds := credsFromQuery(mustParseQuery(t, ""), "", ""); creds != nil {
+		t.Errorf("expected nil credentials, got %v", creds)
+	}
+
+	// static keys with no provider/role_arn are used as-is.
+	creds := credsFromQuery(mustParseQuery(t, ""), "access", "secret")
+	if creds == nil {
+		t.Fatal("expected non-nil credentials")
+	}
+	v, err := creds.Get()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if v.AccessKeyID != "access" || v.SecretAccessKey != "secret" {
+		t.Errorf("got %+v, want access/secret", v)
+	}
+}
+
+func TestIntQueryParam(t *testing.T) {
+	cases := []struct {
+		name    string
+		query   string
+		def     int
+		want    int
+		wantErr bool
+	}{
+		{name: "absent uses default", query: "", def: 8, want: 8},
+		{name: "present", query: "n=16", def: 8, want: 16},
+		{name: "zero is allowed", query: "n=0", def: 8, want: 0},
+		{name: "negative rejected", query: "n=-1", def: 8, wantErr: true},
+		{name: "non-numeric rejected", query: "n=nope", def: 8, wantErr: true},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			got, err := intQueryParam(mustParseQuery(t, tc.query), "n", tc.def)
+			if tc.wantErr {
+				if err == nil {
+					t.Fatalf("expected an error, got none")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if got != tc.want {
+				t.Errorf("got %d, want %d", got, tc.want)
+			}
+		})
+	}
+}
+
+func TestXorCursor(t *testing.T) {
+	id := "01C860Z3M9A7WHJ00000000000"
+	flipped := xorCursor(id)
+	if flipped == id {
+		t.Fatalf("expected xorCursor to change the id")
+	}
+	if back := xorCursor(flipped); back != id {
+		t.Errorf("xorCursor isn't its own inverse: got %q, want %q", back, id)
+	}
+}
+
+func TestIndexKey(t *testing.T) {
+	createdAt := common.DateTime(time.Date(2026, 7, 26, 15, 4, 5, 0, time.UTC))
+	key := indexKey("my-app", createdAt, "call-id")
+	want := "idx/my-app/2026072615/" + xorCursor("call-id")
+	if key != want {
+		t.Errorf("got %q, want %q", key, want)
+	}
+}
+
+func TestIndexPrefixes(t *testing.T) {
+	t.Run("no time bounds returns the unbounded app root", func(t *testing.T) {
+		prefixes := indexPrefixes(&models.CallFilter{AppName: "my-app"})
+		if len(prefixes) != 1 || prefixes[0].prefix != "idx/my-app/" || prefixes[0].bucket != "" {
+			t.Errorf("got %+v, want a single unbounded root prefix", prefixes)
+		}
+	})
+
+	t.Run("bounded range covers every hour bucket inclusive", func(t *testing.T) {
+		from := common.DateTime(time.Date(2026, 7, 26, 10, 0, 0, 0, time.UTC))
+		to := common.DateTime(time.Date(2026, 7, 26, 12, 0, 0, 0, time.UTC))
+		prefixes := indexPrefixes(&models.CallFilter{AppName: "my-app", FromTime: from, ToTime: to})
+		want := []indexPrefix{
+			{prefix: "idx/my-app/2026072610/", bucket: "2026072610"},
+			{prefix: "idx/my-app/2026072611/", bucket: "2026072611"},
+			{prefix: "idx/my-app/2026072612/", bucket: "2026072612"},
+		}
+		if len(prefixes) != len(want) {
+			t.Fatalf("got %d prefixes, want %d: %+v", len(prefixes), len(want), prefixes)
+		}
+		for i := range want {
+			if prefixes[i] != want[i] {
+				t.Errorf("prefixes[%d] = %+v, want %+v", i, prefixes[i], want[i])
+			}
+		}
+	})
+}
+
+func TestUlidBucket(t *testing.T) {
+	// 01ARZ3NDEK is the timestamp portion of the canonical ULID spec
+	// example, which encodes 1469918176385ms.
+	bucket, err := ulidBucket("01ARZ3NDEKTSV4RRFFQ69G5FAV")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	want := time.Unix(0, 1469918176385*int64(time.Millisecond)).UTC().Format("2006010215")
+	if bucket != want {
+		t.Errorf("got %q, want %q", bucket, want)
+	}
+
+	if _, err := ulidBucket("too-short"); err == nil {
+		t.Errorf("expected an error for a too-short id")
+	}
+}
+
+// Note: GetCalls/listIndexEntries aren't covered here -- they drive
+// *s3.S3 directly rather than through an interface, so there's no seam to
+// substitute a fake S3 without a larger refactor (introducing an
+// s3iface.S3API-shaped field). The regression this was meant to catch (a
+// Path/Status filter discarding matches instead of paging for more within
+// the same prefix) is exercised instead by construction: listIndexEntries
+// now applies filter.Path/filter.Status inside its own paging loop, so
+// `want` always counts matching entries, never raw ones.